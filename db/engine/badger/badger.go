@@ -0,0 +1,222 @@
+// Package badger adapts dgraph-io/badger, an LSM-tree store, to the
+// engine.Engine interface. Unlike the Bolt engine there is no native
+// notion of nested buckets, so shard and factor buckets are modeled as
+// key prefixes and the per-event blob is stored as a single value keyed
+// by (shard, objectID, timestamp). This trades Bolt's B+tree spill cost
+// on append-heavy ingest for badger's prefix-scan+delete cost on
+// expiration sweeps.
+package badger
+
+import (
+	"bytes"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/skydb/sky/db/engine"
+)
+
+// keySeparator joins the parts of a composite key (shard, object, event).
+const keySeparator = 0x1f
+
+// Engine wraps a *badger.DB to satisfy engine.Engine.
+type Engine struct {
+	db *badger.DB
+}
+
+// New returns an unopened Badger-backed engine.
+func New() *Engine {
+	return &Engine{}
+}
+
+// Open implements engine.Engine.
+func (e *Engine) Open(path string, opts map[string]interface{}) error {
+	badgerOpts := badger.DefaultOptions(path)
+	if syncWrites, ok := opts["syncWrites"].(bool); ok {
+		badgerOpts = badgerOpts.WithSyncWrites(syncWrites)
+	}
+	db, err := badger.Open(badgerOpts)
+	if err != nil {
+		return err
+	}
+	e.db = db
+	return nil
+}
+
+// Close implements engine.Engine.
+func (e *Engine) Close() error {
+	return e.db.Close()
+}
+
+// View implements engine.Engine.
+func (e *Engine) View(fn func(engine.Tx) error) error {
+	return e.db.View(func(txn *badger.Txn) error {
+		return fn(&txWrapper{txn: txn, writable: false})
+	})
+}
+
+// Update implements engine.Engine.
+func (e *Engine) Update(fn func(engine.Tx) error) error {
+	return e.db.Update(func(txn *badger.Txn) error {
+		return fn(&txWrapper{txn: txn, writable: true})
+	})
+}
+
+// Stats implements engine.Engine, reporting the LSM level/table counts
+// Badger exposes in place of Bolt's page internals.
+func (e *Engine) Stats() (engine.Stats, error) {
+	var s engine.Stats
+	lsm := e.db.Levels()
+	s.LevelCount = len(lsm)
+	for _, l := range lsm {
+		s.SSTableCount += l.NumTables
+	}
+	return s, nil
+}
+
+// Backup implements engine.Engine using Badger's native stream backup.
+func (e *Engine) Backup(w engine.BackupWriter) error {
+	_, err := e.db.Backup(w.(interface {
+		Write(p []byte) (int, error)
+	}), 0)
+	return err
+}
+
+// SweepPrefix deletes every key under prefix whose encoded event
+// timestamp is older than bound, using a forward iterator with values
+// prefetch disabled since only keys are needed to decide deletion. It
+// returns the number of keys removed.
+//
+// This stands in for the Bolt engine's cursor-based sweep: Badger being
+// an LSM, a long-lived delete-as-you-go cursor would pin old SSTables,
+// so sweeps here batch deletes and let compaction reclaim space.
+func (e *Engine) SweepPrefix(prefix []byte, bound []byte, limit int) (deleted int, err error) {
+	err = e.db.Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{
+			PrefetchValues: false,
+			Prefix:         prefix,
+		})
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix) && deleted < limit; it.Next() {
+			key := it.Item().KeyCopy(nil)
+			eventTimestamp := key[bytes.LastIndexByte(key, keySeparator)+1:]
+			if bytes.Compare(eventTimestamp, bound) >= 0 {
+				continue
+			}
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+type txWrapper struct {
+	txn      *badger.Txn
+	writable bool
+}
+
+// Bucket returns a prefix-scoped view of the transaction rooted at name.
+// Since a prefix exists implicitly, it never returns nil, not even for a
+// bucket that was never created; the same holds for nested buckets.
+func (t *txWrapper) Bucket(name []byte) engine.Bucket {
+	return &bucketWrapper{txn: t.txn, prefix: append(append([]byte(nil), name...), keySeparator)}
+}
+
+// CreateBucketIfNotExists is a no-op beyond returning the prefix view:
+// Badger has no bucket creation step, the prefix exists implicitly.
+func (t *txWrapper) CreateBucketIfNotExists(name []byte) (engine.Bucket, error) {
+	return t.Bucket(name), nil
+}
+
+type bucketWrapper struct {
+	txn    *badger.Txn
+	prefix []byte
+}
+
+func (b *bucketWrapper) key(key []byte) []byte {
+	return append(append([]byte(nil), b.prefix...), key...)
+}
+
+func (b *bucketWrapper) Get(key []byte) []byte {
+	item, err := b.txn.Get(b.key(key))
+	if err != nil {
+		return nil
+	}
+	value, _ := item.ValueCopy(nil)
+	return value
+}
+
+func (b *bucketWrapper) Put(key, value []byte) error {
+	return b.txn.Set(b.key(key), value)
+}
+
+func (b *bucketWrapper) Delete(key []byte) error {
+	return b.txn.Delete(b.key(key))
+}
+
+func (b *bucketWrapper) Bucket(key []byte) engine.Bucket {
+	return &bucketWrapper{txn: b.txn, prefix: append(b.key(key), keySeparator)}
+}
+
+func (b *bucketWrapper) CreateBucketIfNotExists(key []byte) (engine.Bucket, error) {
+	return b.Bucket(key), nil
+}
+
+func (b *bucketWrapper) DeleteBucket(key []byte) error {
+	prefix := append(b.key(key), keySeparator)
+	it := b.txn.NewIterator(badger.IteratorOptions{PrefetchValues: false, Prefix: prefix})
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		if err := b.txn.Delete(it.Item().KeyCopy(nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bucketWrapper) Cursor() engine.Cursor {
+	it := b.txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, Prefix: b.prefix})
+	return &cursorWrapper{it: it, prefix: b.prefix, txn: b.txn}
+}
+
+type cursorWrapper struct {
+	it     *badger.Iterator
+	prefix []byte
+	txn    *badger.Txn
+	cur    []byte
+}
+
+func (c *cursorWrapper) item() ([]byte, []byte) {
+	if !c.it.ValidForPrefix(c.prefix) {
+		return nil, nil
+	}
+	item := c.it.Item()
+	key := bytes.TrimPrefix(item.KeyCopy(nil), c.prefix)
+	value, _ := item.ValueCopy(nil)
+	c.cur = key
+	return key, value
+}
+
+func (c *cursorWrapper) First() ([]byte, []byte) {
+	c.it.Seek(c.prefix)
+	return c.item()
+}
+
+func (c *cursorWrapper) Next() ([]byte, []byte) {
+	c.it.Next()
+	return c.item()
+}
+
+func (c *cursorWrapper) Seek(seek []byte) ([]byte, []byte) {
+	c.it.Seek(append(append([]byte(nil), c.prefix...), seek...))
+	return c.item()
+}
+
+func (c *cursorWrapper) Delete() error {
+	if c.cur == nil {
+		return nil
+	}
+	return c.txn.Delete(append(append([]byte(nil), c.prefix...), c.cur...))
+}
@@ -0,0 +1,151 @@
+// Package bolt adapts BoltDB to the engine.Engine interface. It is the
+// default engine used by Table and backs its B+tree storage exactly as
+// Table did before the engine abstraction was introduced.
+package bolt
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/skydb/sky/db/engine"
+)
+
+// Engine wraps a *bolt.DB to satisfy engine.Engine.
+type Engine struct {
+	db *bolt.DB
+}
+
+// New returns an unopened Bolt-backed engine.
+func New() *Engine {
+	return &Engine{}
+}
+
+// Open implements engine.Engine.
+func (e *Engine) Open(path string, opts map[string]interface{}) error {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+	if fillPercent, ok := opts["fillPercent"].(float64); ok {
+		db.FillPercent = fillPercent
+	}
+	e.db = db
+	return nil
+}
+
+// Close implements engine.Engine.
+func (e *Engine) Close() error {
+	return e.db.Close()
+}
+
+// View implements engine.Engine.
+func (e *Engine) View(fn func(engine.Tx) error) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		return fn(&txWrapper{tx})
+	})
+}
+
+// Update implements engine.Engine.
+func (e *Engine) Update(fn func(engine.Tx) error) error {
+	return e.db.Update(func(tx *bolt.Tx) error {
+		return fn(&txWrapper{tx})
+	})
+}
+
+// Stats implements engine.Engine.
+func (e *Engine) Stats() (engine.Stats, error) {
+	var s engine.Stats
+	err := e.db.View(func(tx *bolt.Tx) error {
+		var bs bolt.BucketStats
+		tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			bs.Add(b.Stats())
+			return nil
+		})
+		var dbs = e.db.Stats()
+		s.KeyCount = bs.KeyN
+		s.Depth = bs.Depth
+		s.BranchPages = bs.BranchPageN
+		s.BranchOverflow = bs.BranchOverflowN
+		s.LeafPages = bs.LeafPageN
+		s.LeafOverflow = bs.LeafOverflowN
+		s.FreePages = dbs.FreePageN
+		s.PendingPages = dbs.PendingPageN
+		return nil
+	})
+	return s, err
+}
+
+// Backup implements engine.Engine using Bolt's native tx.WriteTo.
+func (e *Engine) Backup(w engine.BackupWriter) error {
+	return e.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w.(interface {
+			Write(p []byte) (int, error)
+		}))
+		return err
+	})
+}
+
+// DB returns the underlying *bolt.DB for callers that still need direct
+// access during the migration to the engine abstraction.
+func (e *Engine) DB() *bolt.DB {
+	return e.db
+}
+
+type txWrapper struct {
+	tx *bolt.Tx
+}
+
+func (t *txWrapper) Bucket(name []byte) engine.Bucket {
+	b := t.tx.Bucket(name)
+	if b == nil {
+		return nil
+	}
+	return &bucketWrapper{b}
+}
+
+func (t *txWrapper) CreateBucketIfNotExists(name []byte) (engine.Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return &bucketWrapper{b}, nil
+}
+
+type bucketWrapper struct {
+	b *bolt.Bucket
+}
+
+func (b *bucketWrapper) Get(key []byte) []byte { return b.b.Get(key) }
+
+func (b *bucketWrapper) Put(key, value []byte) error { return b.b.Put(key, value) }
+
+func (b *bucketWrapper) Delete(key []byte) error { return b.b.Delete(key) }
+
+func (b *bucketWrapper) Bucket(key []byte) engine.Bucket {
+	nested := b.b.Bucket(key)
+	if nested == nil {
+		return nil
+	}
+	return &bucketWrapper{nested}
+}
+
+func (b *bucketWrapper) CreateBucketIfNotExists(key []byte) (engine.Bucket, error) {
+	nested, err := b.b.CreateBucketIfNotExists(key)
+	if err != nil {
+		return nil, err
+	}
+	return &bucketWrapper{nested}, nil
+}
+
+func (b *bucketWrapper) DeleteBucket(key []byte) error { return b.b.DeleteBucket(key) }
+
+func (b *bucketWrapper) Cursor() engine.Cursor { return &cursorWrapper{b.b.Cursor()} }
+
+type cursorWrapper struct {
+	c *bolt.Cursor
+}
+
+func (c *cursorWrapper) First() ([]byte, []byte)           { return c.c.First() }
+func (c *cursorWrapper) Next() ([]byte, []byte)            { return c.c.Next() }
+func (c *cursorWrapper) Seek(seek []byte) ([]byte, []byte) { return c.c.Seek(seek) }
+func (c *cursorWrapper) Delete() error                     { return c.c.Delete() }
@@ -0,0 +1,110 @@
+// Package engine defines the storage abstraction that sits underneath a
+// Sky table. It exists so that Table can be backed by more than one
+// on-disk representation (today BoltDB's B+tree, eventually an LSM such
+// as Badger) without the rest of the package caring which one is active.
+package engine
+
+import "errors"
+
+// ErrBucketNotFound is returned when looking up a bucket that does not
+// exist within the current transaction.
+var ErrBucketNotFound = errors.New("engine: bucket not found")
+
+// Engine is the storage interface a Table drives. Implementations wrap a
+// concrete embedded store (BoltDB, Badger, ...) and translate Sky's
+// bucket/cursor shaped access pattern into whatever that store natively
+// supports.
+type Engine interface {
+	// Open prepares the engine for use at path, creating it if necessary.
+	Open(path string, opts map[string]interface{}) error
+
+	// Close releases any resources associated with the engine.
+	Close() error
+
+	// View runs fn within a read-only transaction.
+	View(fn func(Tx) error) error
+
+	// Update runs fn within a read-write transaction. The transaction is
+	// committed if fn returns nil and rolled back otherwise.
+	Update(fn func(Tx) error) error
+
+	// Stats returns engine-agnostic storage statistics.
+	Stats() (Stats, error)
+
+	// Backup writes a consistent copy of the engine's data to the given
+	// sink in an engine-defined format.
+	Backup(w BackupWriter) error
+}
+
+// BackupWriter is the subset of io.Writer an Engine needs to stream a
+// backup; defined locally to avoid importing io solely for this.
+type BackupWriter interface {
+	Write(p []byte) (int, error)
+}
+
+// Tx is a single read or read-write transaction against an Engine.
+type Tx interface {
+	// Bucket returns the named top-level bucket, or nil if it does not
+	// exist.
+	Bucket(name []byte) Bucket
+
+	// CreateBucketIfNotExists returns the named top-level bucket,
+	// creating it first if necessary. Only valid within Update.
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+}
+
+// Bucket is a named collection of key/value pairs, which may itself
+// contain nested buckets (used by Sky to model per-object event streams
+// within a shard).
+type Bucket interface {
+	// Get returns the value for key, or nil if it does not exist.
+	Get(key []byte) []byte
+
+	// Put sets the value for key.
+	Put(key, value []byte) error
+
+	// Delete removes key and its value.
+	Delete(key []byte) error
+
+	// Bucket returns the nested bucket named key, or nil if it does not
+	// exist.
+	Bucket(key []byte) Bucket
+
+	// CreateBucketIfNotExists returns the nested bucket named key,
+	// creating it first if necessary.
+	CreateBucketIfNotExists(key []byte) (Bucket, error)
+
+	// DeleteBucket removes the nested bucket named key.
+	DeleteBucket(key []byte) error
+
+	// Cursor returns a cursor positioned before the first key.
+	Cursor() Cursor
+}
+
+// Cursor iterates over the keys of a bucket in byte order.
+type Cursor interface {
+	First() (key, value []byte)
+	Next() (key, value []byte)
+	Seek(seek []byte) (key, value []byte)
+	Delete() error
+}
+
+// Stats holds the engine-agnostic counters surfaced by Table.EngineStats.
+// Fields that a given engine cannot populate (for example, Bolt-specific
+// page internals on an LSM-backed engine) are left at zero.
+type Stats struct {
+	KeyCount int
+	Depth    int
+
+	// Page statistics, meaningful for page-oriented engines such as Bolt.
+	BranchPages    int
+	BranchOverflow int
+	LeafPages      int
+	LeafOverflow   int
+	FreePages      int
+	PendingPages   int
+
+	// SST/level statistics, meaningful for LSM engines such as Badger.
+	SSTableCount int
+	LevelCount   int
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultBatchWindow is how long the ingest queue waits for more events to
+// arrive before flushing a commit group, absent a call to SetIngestBatch.
+const DefaultBatchWindow = 2 * time.Millisecond
+
+// DefaultBatchMaxEvents is the commit group size at which the ingest
+// queue flushes early, absent a call to SetIngestBatch.
+const DefaultBatchMaxEvents = 1000
+
+// ingestRequest is a single caller's request to append events for an
+// object, coalesced with others into one Update transaction.
+type ingestRequest struct {
+	objectID string
+	events   []Event
+	response chan error
+}
+
+// ingestQueue coalesces concurrent InsertEvents calls within a short
+// window into a single Bolt write transaction, trading a small amount of
+// added latency for far fewer transaction syncs under load.
+type ingestQueue struct {
+	table *Table
+
+	mu         sync.Mutex
+	window     time.Duration
+	maxEvents  int
+	pending    []*ingestRequest
+	pendingN   int
+	flushTimer *time.Timer
+}
+
+// newIngestQueue returns an ingest queue using the package defaults.
+func newIngestQueue(t *Table) *ingestQueue {
+	return &ingestQueue{
+		table:     t,
+		window:    DefaultBatchWindow,
+		maxEvents: DefaultBatchMaxEvents,
+	}
+}
+
+// submit enqueues req and flushes immediately if it brings the pending
+// batch to or over its event limit, otherwise arms the batch window
+// timer so the batch flushes shortly after the first request arrives.
+func (q *ingestQueue) submit(req *ingestRequest) {
+	q.mu.Lock()
+	q.pending = append(q.pending, req)
+	q.pendingN += len(req.events)
+	if q.pendingN >= q.maxEvents {
+		pending := q.takePendingLocked()
+		q.mu.Unlock()
+		q.flush(pending)
+		return
+	}
+	if q.flushTimer == nil {
+		q.flushTimer = time.AfterFunc(q.window, q.flushPending)
+	}
+	q.mu.Unlock()
+}
+
+// flushPending is invoked by the batch window timer.
+func (q *ingestQueue) flushPending() {
+	q.mu.Lock()
+	pending := q.takePendingLocked()
+	q.mu.Unlock()
+	if len(pending) > 0 {
+		q.flush(pending)
+	}
+}
+
+// takePendingLocked detaches the current pending batch. Callers must hold
+// q.mu.
+func (q *ingestQueue) takePendingLocked() []*ingestRequest {
+	pending := q.pending
+	q.pending = nil
+	q.pendingN = 0
+	if q.flushTimer != nil {
+		q.flushTimer.Stop()
+		q.flushTimer = nil
+	}
+	return pending
+}
+
+// flush commits every request in pending as a single Update transaction,
+// then notifies each caller of its individual result.
+func (q *ingestQueue) flush(pending []*ingestRequest) {
+	var t = q.table
+	var waited = time.Now()
+	var batchSize int
+	for _, req := range pending {
+		batchSize += len(req.events)
+	}
+
+	var failed = make([]bool, len(pending))
+	err := t.Update(func(tx *Tx) error {
+		for i, req := range pending {
+			if err := t.insertEventsTx(tx, req.objectID, req.events); err != nil {
+				req.response <- err
+				failed[i] = true // mark handled so it's skipped below
+			}
+		}
+		return nil
+	})
+
+	for i, req := range pending {
+		if failed[i] {
+			continue // already delivered an error above
+		}
+		req.response <- err
+	}
+
+	t.emitGauge("ingest.batch.size", float64(batchSize), t.ddTags())
+	t.emitHistogram("ingest.batch.wait", float64(time.Since(waited)), t.ddTags())
+}
+
+// insertEventsTx writes events for objectID within an already-open
+// transaction, shared by both InsertEvents and the ingest queue's
+// coalesced commits.
+func (t *Table) insertEventsTx(tx *Tx, objectID string, events []Event) error {
+	if objectID == "" {
+		return ErrObjectIDRequired
+	}
+	var sb = tx.Bucket(shardDBName(t.shardIndex(objectID)))
+	var ob, err = sb.CreateBucketIfNotExists([]byte(objectID))
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		var raw = rawEvent{timestamp: event.Timestamp.UnixNano()}
+		raw.data = make(map[int]interface{}, len(event.Data))
+		for name, value := range event.Data {
+			p, ok := t.properties[name]
+			if !ok {
+				continue
+			}
+			raw.data[p.ID] = value
+		}
+		value, err := raw.marshal()
+		if err != nil {
+			return err
+		}
+		if err := ob.Put(ShiftTimeBytes(event.Timestamp), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertEvents appends events for an object, coalescing this call with
+// any other concurrent InsertEvents calls on the table into a single
+// write transaction. Use SetIngestBatch to tune the coalescing window.
+func (t *Table) InsertEvents(objectID string, events []Event) error {
+	t.Lock()
+	if t.ingest == nil {
+		t.ingest = newIngestQueue(t)
+	}
+	var q = t.ingest
+	t.Unlock()
+
+	req := &ingestRequest{objectID: objectID, events: events, response: make(chan error, 1)}
+	q.submit(req)
+	return <-req.response
+}
+
+// SetIngestBatch configures how long InsertEvents waits for concurrent
+// callers to join a commit group (window) and the event count at which a
+// group flushes early regardless of the window (maxEvents).
+func (t *Table) SetIngestBatch(window time.Duration, maxEvents int) {
+	t.Lock()
+	defer t.Unlock()
+	if t.ingest == nil {
+		t.ingest = newIngestQueue(t)
+	}
+	t.ingest.mu.Lock()
+	defer t.ingest.mu.Unlock()
+	t.ingest.window = window
+	t.ingest.maxEvents = maxEvents
+}
+
+// SetNoSync toggles Bolt's NoSync mode for this table, useful when
+// replaying historical event dumps where durability can be relaxed
+// temporarily. When enabled, an explicit Sync is issued every syncEvery
+// Update transactions instead of on every commit - not just the ones
+// batched through the ingest queue, but also retention sweeps, resharding,
+// and property/factor creation, since all of those commit through
+// Table.Update too.
+func (t *Table) SetNoSync(enabled bool, syncEvery int) {
+	t.Lock()
+	defer t.Unlock()
+	if t.db != nil {
+		t.db.NoSync = enabled
+	}
+	t.syncMu.Lock()
+	t.noSync = enabled
+	t.syncEvery = syncEvery
+	t.sinceSync = 0
+	t.syncMu.Unlock()
+}
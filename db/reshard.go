@@ -0,0 +1,241 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/skydb/sky/hash"
+)
+
+// ShardingStrategy selects how Table.shardIndex maps an object id to a
+// shard. Modulo is the strategy Sky has always used; Rendezvous trades a
+// slightly more expensive lookup for the property that changing the
+// shard count only moves ~1/N of objects instead of rehashing everything.
+type ShardingStrategy int
+
+const (
+	// ShardingModulo routes objects by hash.Local(id) % shardCount. A
+	// shard count change rehashes almost every object.
+	ShardingModulo ShardingStrategy = iota
+
+	// ShardingRendezvous routes objects by highest random weight over
+	// the current shard set, so a shard count change only reassigns the
+	// objects that actually land on a different shard. Reshard still
+	// copies every object through the staging buckets under either
+	// strategy.
+	ShardingRendezvous
+)
+
+// String returns the JSON-friendly name of the strategy.
+func (s ShardingStrategy) String() string {
+	switch s {
+	case ShardingRendezvous:
+		return "rendezvous"
+	default:
+		return "modulo"
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s ShardingStrategy) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *ShardingStrategy) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case `"rendezvous"`:
+		*s = ShardingRendezvous
+	default:
+		*s = ShardingModulo
+	}
+	return nil
+}
+
+// SetShardingStrategy selects the strategy used by future shardIndex
+// lookups and resharding operations. It does not itself move any data;
+// call Reshard afterwards if objects need to be redistributed under the
+// new strategy.
+func (t *Table) SetShardingStrategy(strategy ShardingStrategy) {
+	t.Lock()
+	defer t.Unlock()
+	t.shardingStrategy = strategy
+}
+
+// rendezvousShardIndex returns the shard with the highest random weight
+// for id among shardCount candidates, per the rendezvous (HRW) hashing
+// scheme.
+func rendezvousShardIndex(id string, shardCount int) int {
+	var best int
+	var bestScore uint64
+	for i := 0; i < shardCount; i++ {
+		h := fnv.New64a()
+		h.Write([]byte(id))
+		h.Write([]byte{':'})
+		binary.Write(h, binary.BigEndian, uint32(i))
+		if score := h.Sum64(); i == 0 || score > bestScore {
+			bestScore = score
+			best = i
+		}
+	}
+	return best
+}
+
+// reshardShardDBName returns the name of the temporary shard bucket used
+// to stage objects being migrated to index during a Reshard.
+func reshardShardDBName(index int) []byte {
+	return []byte(fmt.Sprintf("reshard/%d", index))
+}
+
+// Reshard changes the table's shard count, redistributing every object
+// to its new shard. It proceeds in bounded batches sized like the
+// expiration sweeper so that no single transaction grows unbounded, and
+// records its progress (reshardFromShard, reshardCursor) in the meta
+// bucket so a crash mid-rebalance resumes rather than restarts.
+//
+// Reshard blocks until the migration completes. Callers that want a
+// background rebalance should call it from their own goroutine.
+func (t *Table) Reshard(newShardCount int) error {
+	t.Lock()
+	defer t.Unlock()
+	if !t.opened() {
+		return fmt.Errorf("table not open")
+	}
+	if newShardCount <= 0 {
+		return fmt.Errorf("reshard: new shard count must be positive")
+	}
+
+	// Start a fresh reshard, or resume one left in progress by a prior
+	// crash. A resume must target the same new shard count; starting
+	// over with a different count would leave a mix of old- and
+	// new-strategy objects in the staging buckets.
+	if t.reshardNewCount != 0 && t.reshardNewCount != newShardCount {
+		return fmt.Errorf("reshard: already in progress to %d shards, finish or resume that first", t.reshardNewCount)
+	}
+	if t.reshardNewCount == 0 {
+		t.reshardNewCount = newShardCount
+		t.reshardFromShard = 0
+		t.reshardCursor = nil
+		if err := t.Update(func(tx *Tx) error { return tx.PutMeta() }); err != nil {
+			return err
+		}
+	}
+
+	for t.reshardFromShard < t.shardCount {
+		if _, err := t.reshardBatch(newShardCount); err != nil {
+			return err
+		}
+	}
+
+	return t.finishReshard(newShardCount)
+}
+
+// reshardBatch migrates up to SweepBatchSize objects from the shard
+// currently being drained into the new, strategy-recomputed staging
+// buckets. It returns true once that source shard is fully drained.
+func (t *Table) reshardBatch(newShardCount int) (done bool, err error) {
+	err = t.Update(func(tx *Tx) error {
+		var sb = tx.Bucket(shardDBName(t.reshardFromShard))
+		var sc = sb.Cursor()
+
+		var objectKey []byte
+		var migrated int
+		for ; migrated < SweepBatchSize; migrated++ {
+			if t.reshardCursor == nil {
+				objectKey, _ = sc.First()
+			} else {
+				// The object at the cursor has normally been migrated and
+				// deleted already, so Seek lands on the next one; only step
+				// past the cursor if it is still present.
+				objectKey, _ = sc.Seek(t.reshardCursor)
+				if bytes.Equal(objectKey, t.reshardCursor) {
+					objectKey, _ = sc.Next()
+				}
+			}
+			if objectKey == nil {
+				done = true
+				t.reshardFromShard++
+				t.reshardCursor = nil
+				break
+			}
+			t.reshardCursor = append([]byte(nil), objectKey...)
+
+			var dest int
+			if t.shardingStrategy == ShardingRendezvous {
+				dest = rendezvousShardIndex(string(objectKey), newShardCount)
+			} else {
+				dest = int(hash.Local(string(objectKey))) % newShardCount
+			}
+
+			var destBucket = tx.Bucket(reshardShardDBName(dest))
+			if destBucket == nil {
+				destBucket, err = tx.CreateBucketIfNotExists(reshardShardDBName(dest))
+				if err != nil {
+					return err
+				}
+			}
+			destObject, err := destBucket.CreateBucketIfNotExists(objectKey)
+			if err != nil {
+				return err
+			}
+			var ob = sb.Bucket(objectKey)
+			var oc = ob.Cursor()
+			for eventKey, eventValue := oc.First(); eventKey != nil; eventKey, eventValue = oc.Next() {
+				if err := destObject.Put(eventKey, eventValue); err != nil {
+					return err
+				}
+			}
+			sb.DeleteBucket(objectKey)
+		}
+		return tx.PutMeta()
+	})
+	return done, err
+}
+
+// finishReshard swaps the staged shard buckets into place and updates
+// the table's shard count. Bolt has no bucket-rename primitive, so the
+// swap walks each staging bucket and copies its contents (every object
+// reshardBatch migrated into it) into the final name before discarding
+// the staging bucket, all within this single transaction.
+func (t *Table) finishReshard(newShardCount int) error {
+	return t.Update(func(tx *Tx) error {
+		for i := 0; i < t.shardCount; i++ {
+			if old := tx.Bucket(shardDBName(i)); old != nil {
+				tx.DeleteBucket(shardDBName(i))
+			}
+		}
+		for i := 0; i < newShardCount; i++ {
+			final, err := tx.CreateBucketIfNotExists(shardDBName(i))
+			if err != nil {
+				return err
+			}
+			staged := tx.Bucket(reshardShardDBName(i))
+			if staged == nil {
+				continue
+			}
+			sc := staged.Cursor()
+			for objectKey, _ := sc.First(); objectKey != nil; objectKey, _ = sc.Next() {
+				destObject, err := final.CreateBucketIfNotExists(objectKey)
+				if err != nil {
+					return err
+				}
+				srcObject := staged.Bucket(objectKey)
+				oc := srcObject.Cursor()
+				for eventKey, eventValue := oc.First(); eventKey != nil; eventKey, eventValue = oc.Next() {
+					if err := destObject.Put(eventKey, eventValue); err != nil {
+						return err
+					}
+				}
+			}
+			tx.DeleteBucket(reshardShardDBName(i))
+		}
+
+		t.shardCount = newShardCount
+		t.reshardNewCount = 0
+		t.reshardFromShard = 0
+		t.reshardCursor = nil
+		return tx.PutMeta()
+	})
+}
@@ -0,0 +1,347 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRetentionPolicyNameRequired is returned when creating or altering a
+	// retention policy without a name.
+	ErrRetentionPolicyNameRequired = errors.New("retention policy name required")
+
+	// ErrRetentionPolicyExists is returned when creating a retention policy
+	// whose name is already in use on the table.
+	ErrRetentionPolicyExists = errors.New("retention policy already exists")
+
+	// ErrRetentionPolicyNotFound is returned when dropping or altering a
+	// retention policy that does not exist on the table.
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+
+	// DefaultRetentionCheckInterval is how often the retention service
+	// wakes up to look for policies that need sweeping.
+	DefaultRetentionCheckInterval = 10 * time.Second
+)
+
+// RetentionPolicy describes how long events are kept before being swept by
+// the retention service, optionally scoped to a single property.
+type RetentionPolicy struct {
+	// Name uniquely identifies the policy within a table.
+	Name string `json:"name"`
+
+	// Duration is how long an event is retained, measured from the time
+	// the sweep runs back to the event's timestamp.
+	Duration time.Duration `json:"duration"`
+
+	// Measurement, if set, restricts sweeping to events that carry this
+	// property; events without it are left in place even past the
+	// policy's duration. An empty value matches every event. If the
+	// named property no longer exists on the table, the policy matches
+	// nothing rather than falling back to sweeping everything.
+	Measurement string `json:"measurement,omitempty"`
+
+	// ShardGroupDuration hints at how objects falling under this policy
+	// should eventually be grouped when sharding is retention-aware.
+	ShardGroupDuration time.Duration `json:"shardGroupDuration,omitempty"`
+}
+
+// retentionProgress tracks where the sweeper left off for a single
+// retention policy so that successive batches resume rather than restart.
+type retentionProgress struct {
+	currentShard  int    // index of the shard currently being swept
+	currentObject []byte // key of the last object swept in that shard
+}
+
+// CreateRetentionPolicy adds a new retention policy to the table and
+// persists it to the meta bucket.
+func (t *Table) CreateRetentionPolicy(rp *RetentionPolicy) error {
+	t.Lock()
+	defer t.Unlock()
+	if rp.Name == "" {
+		return ErrRetentionPolicyNameRequired
+	}
+	if _, ok := t.retentionPolicies[rp.Name]; ok {
+		return ErrRetentionPolicyExists
+	}
+
+	return t.Update(func(tx *Tx) error {
+		if t.retentionPolicies == nil {
+			t.retentionPolicies = make(map[string]*RetentionPolicy)
+		}
+		t.retentionPolicies[rp.Name] = rp
+		return tx.PutMeta()
+	})
+}
+
+// DropRetentionPolicy removes a retention policy and its sweep progress
+// from the table.
+func (t *Table) DropRetentionPolicy(name string) error {
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.retentionPolicies[name]; !ok {
+		return ErrRetentionPolicyNotFound
+	}
+
+	return t.Update(func(tx *Tx) error {
+		delete(t.retentionPolicies, name)
+		delete(t.retentionProgress, name)
+		return tx.PutMeta()
+	})
+}
+
+// AlterRetentionPolicy replaces the definition of an existing retention
+// policy, identified by name, with rp. The sweep progress for the policy
+// is left untouched so an altered policy keeps making forward progress.
+func (t *Table) AlterRetentionPolicy(name string, rp *RetentionPolicy) error {
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.retentionPolicies[name]; !ok {
+		return ErrRetentionPolicyNotFound
+	}
+	if rp.Name == "" {
+		rp.Name = name
+	}
+
+	return t.Update(func(tx *Tx) error {
+		delete(t.retentionPolicies, name)
+		t.retentionPolicies[rp.Name] = rp
+		if rp.Name != name {
+			if p, ok := t.retentionProgress[name]; ok {
+				delete(t.retentionProgress, name)
+				t.retentionProgress[rp.Name] = p
+			}
+		}
+		return tx.PutMeta()
+	})
+}
+
+// RetentionPolicies returns the retention policies currently defined on
+// the table, keyed by name.
+func (t *Table) RetentionPolicies() map[string]*RetentionPolicy {
+	t.Lock()
+	defer t.Unlock()
+	policies := make(map[string]*RetentionPolicy, len(t.retentionPolicies))
+	for k, v := range t.retentionPolicies {
+		policies[k] = v
+	}
+	return policies
+}
+
+// RetentionProgress returns the shard and object cursor the retention
+// service has reached for a given policy, useful for monitoring.
+func (t *Table) RetentionProgress(name string) (shard int, object []byte) {
+	t.Lock()
+	defer t.Unlock()
+	p, ok := t.retentionProgress[name]
+	if !ok {
+		return 0, nil
+	}
+	return p.currentShard, p.currentObject
+}
+
+// rateLimiter is a simple token bucket shared between the events/sec and
+// bytes/sec dimensions of a sweep, used to keep the retention service from
+// starving writers on busy tables.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	eventRate float64 // events/sec, 0 disables event throttling
+	byteRate  float64 // bytes/sec, 0 disables byte throttling
+
+	eventTokens float64
+	byteTokens  float64
+	last        time.Time
+}
+
+// newRateLimiter returns a rate limiter allowing up to eventsPerSec events
+// and bytesPerSec bytes of sweep work per second. A zero value disables
+// throttling along that dimension.
+func newRateLimiter(eventsPerSec, bytesPerSec float64) *rateLimiter {
+	return &rateLimiter{
+		eventRate:   eventsPerSec,
+		byteRate:    bytesPerSec,
+		eventTokens: eventsPerSec,
+		byteTokens:  bytesPerSec,
+		last:        time.Now(),
+	}
+}
+
+// wait blocks until enough tokens have accumulated to cover events and
+// bytes, refilling both buckets based on elapsed wall-clock time. A
+// request larger than a bucket's one-second capacity waits for a full
+// bucket and leaves it in debt, so later requests wait for the rest.
+func (rl *rateLimiter) wait(events, bytes float64) {
+	if rl.eventRate == 0 && rl.byteRate == 0 {
+		return
+	}
+
+	for {
+		rl.mu.Lock()
+		now := time.Now()
+		elapsed := now.Sub(rl.last).Seconds()
+		rl.last = now
+		if rl.eventRate > 0 {
+			rl.eventTokens += elapsed * rl.eventRate
+			if rl.eventTokens > rl.eventRate {
+				rl.eventTokens = rl.eventRate
+			}
+		}
+		if rl.byteRate > 0 {
+			rl.byteTokens += elapsed * rl.byteRate
+			if rl.byteTokens > rl.byteRate {
+				rl.byteTokens = rl.byteRate
+			}
+		}
+
+		haveEvents := rl.eventRate == 0 || rl.eventTokens >= math.Min(events, rl.eventRate)
+		haveBytes := rl.byteRate == 0 || rl.byteTokens >= math.Min(bytes, rl.byteRate)
+		if haveEvents && haveBytes {
+			if rl.eventRate > 0 {
+				rl.eventTokens -= events
+			}
+			if rl.byteRate > 0 {
+				rl.byteTokens -= bytes
+			}
+			rl.mu.Unlock()
+			return
+		}
+		rl.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// RetentionService periodically sweeps every retention policy defined on a
+// table, deleting events that have fallen outside their policy's duration.
+type RetentionService struct {
+	table    *Table
+	interval time.Duration
+	limiter  *rateLimiter
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRetentionService returns a retention service for t that checks for
+// expired events every interval. A zero interval uses
+// DefaultRetentionCheckInterval.
+func NewRetentionService(t *Table, interval time.Duration) *RetentionService {
+	if interval == 0 {
+		interval = DefaultRetentionCheckInterval
+	}
+	return &RetentionService{
+		table:    t,
+		interval: interval,
+		limiter:  newRateLimiter(0, 0),
+	}
+}
+
+// SetRateLimit configures the events/sec and bytes/sec the service may
+// spend sweeping. A zero value disables throttling along that dimension.
+func (s *RetentionService) SetRateLimit(eventsPerSec, bytesPerSec float64) {
+	s.limiter = newRateLimiter(eventsPerSec, bytesPerSec)
+}
+
+// Start begins sweeping the table's retention policies in the background.
+// It is a no-op if the service is already running.
+func (s *RetentionService) Start() {
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run()
+}
+
+// Stop halts the background sweep and blocks until it has exited.
+func (s *RetentionService) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// run is the service's main loop, invoked in its own goroutine.
+func (s *RetentionService) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sweepAll()
+		}
+	}
+}
+
+// sweepAll sweeps every policy defined on the table exactly once, one
+// batch at a time. The table lock and the rate limiter's throttled wait
+// are deliberately never held at the same time: sweepAll takes the lock
+// only for the duration of a single batch's transaction, then releases
+// it before blocking in s.limiter.wait, so a throttled sweep stalls
+// other table operations for no longer than one batch instead of for
+// its entire, possibly throttle-stretched, run.
+func (s *RetentionService) sweepAll() {
+	t := s.table
+
+	t.Lock()
+	if !t.opened() {
+		t.Unlock()
+		return
+	}
+	policies := make([]*RetentionPolicy, 0, len(t.retentionPolicies))
+	for _, policy := range t.retentionPolicies {
+		policies = append(policies, policy)
+	}
+	t.Unlock()
+
+	for _, policy := range policies {
+		for {
+			t.Lock()
+			if !t.opened() {
+				t.Unlock()
+				return
+			}
+			p := t.retentionProgressFor(policy.Name)
+			var events, objects int
+			var deletedBytes int64
+			t.Update(func(tx *Tx) error {
+				_, events, objects, deletedBytes = t.sweepBatch(tx, policy, p)
+				if events == 0 && objects == 0 {
+					return NoDeletes
+				}
+				return nil
+			})
+			t.Unlock()
+
+			if events == 0 && objects == 0 {
+				break
+			}
+			s.limiter.wait(float64(events), float64(deletedBytes))
+		}
+	}
+}
+
+// SetRetentionService installs svc as the table's retention service,
+// stopping any previously installed one.
+func (t *Table) SetRetentionService(svc *RetentionService) {
+	t.Lock()
+	defer t.Unlock()
+	if t.retentionService != nil {
+		t.retentionService.Stop()
+	}
+	t.retentionService = svc
+}
+
+// RetentionService returns the table's currently installed retention
+// service, or nil if none has been set.
+func (t *Table) RetentionService() *RetentionService {
+	t.Lock()
+	defer t.Unlock()
+	return t.retentionService
+}
@@ -12,7 +12,10 @@ import (
 	"time"
 
 	"github.com/boltdb/bolt"
+	"github.com/skydb/sky/db/engine"
+	boltengine "github.com/skydb/sky/db/engine/bolt"
 	"github.com/skydb/sky/hash"
+	"github.com/skydb/sky/metrics"
 	"github.com/skydb/sky/statsd"
 	"github.com/ugorji/go/codec"
 )
@@ -35,14 +38,38 @@ var (
 	NoDeletes = errors.New("nothing was deleted, rollback instead of commit")
 )
 
-// NewTable returns a reference to a new table.
+// NewTable returns a reference to a new table backed by the default
+// BoltDB engine.
 func NewTable(name, path string) *Table {
 	return &Table{
-		name: name,
-		path: path,
+		name:       name,
+		path:       path,
+		engineName: EngineBolt,
 	}
 }
 
+// NewTableWithEngine returns a reference to a new table backed by the
+// named storage engine. opts are engine-specific tuning knobs, e.g.
+// {"syncWrites": false} for the Badger engine.
+//
+// Only EngineBolt is wired all the way through today; other engine names
+// are recorded on the table so callers can start migrating call sites
+// ahead of the storage layer swap landing.
+func NewTableWithEngine(name, path, engineName string, opts map[string]interface{}) *Table {
+	return &Table{
+		name:       name,
+		path:       path,
+		engineName: engineName,
+		engineOpts: opts,
+	}
+}
+
+// Engine name constants accepted by NewTableWithEngine.
+const (
+	EngineBolt   = "bolt"
+	EngineBadger = "badger"
+)
+
 // Statistics about the table
 type TableStats struct {
 	// Page count statistics
@@ -79,27 +106,51 @@ type Table struct {
 	StrictMode bool
 
 	db             *bolt.DB
+	engine         engine.Engine
 	name           string
 	path           string
+	engineName     string
+	engineOpts     map[string]interface{}
 	caches         map[int]*cache
 	properties     map[string]*Property
 	propertiesByID map[int]*Property
 	stat           Stat
 
-	shardCount     int
-	maxPermanentID int
-	maxTransientID int
+	shardCount       int
+	shardingStrategy ShardingStrategy
+	maxPermanentID   int
+	maxTransientID   int
+
+	// reshard progress, valid while reshardNewCount != 0.
+	reshardNewCount  int
+	reshardFromShard int
+	reshardCursor    []byte
+
+	// expiration sweep state, keyed by retention policy name.
+	retentionPolicies map[string]*RetentionPolicy
+	retentionProgress map[string]*retentionProgress
+	retentionService  *RetentionService
+
+	ingest *ingestQueue   // coalesces concurrent InsertEvents calls
+	sinks  []metrics.Sink // additional metrics sinks beyond the default statsd emission
 
-	// expiration sweep state
-	currentShard  int    // track index of currently swept shard
-	currentObject []byte // track the key of last swept object
+	// NoSync bookkeeping. Guarded by its own mutex rather than the table
+	// lock because Update (where it's checked) is sometimes called while
+	// the table lock is already held by the caller (e.g. CreateRetentionPolicy).
+	syncMu    sync.Mutex
+	noSync    bool
+	syncEvery int
+	sinceSync int
 
 	ddTagsCache []string    // caches DataDog tags
+	statsMu     sync.Mutex  // guards boltStats, since Update runs it outside the table lock
 	boltStats   *bolt.Stats // caches previous snapshot of bolt stats
 }
 
-// SweepNextObject is used internally to implement automatic expiration of events
-// that are older than the global expiration time setting.
+// SweepNextBatch is used internally to implement automatic expiration of events
+// that are older than the given expiration duration. It sweeps against an
+// implicit, unnamed retention policy for callers that have not migrated to
+// CreateRetentionPolicy.
 // Return count of objects that were swept and count of events and objects deleted.
 func (t *Table) SweepNextBatch(expiration time.Duration) (swept, events, objects int) {
 	t.Lock()
@@ -107,62 +158,126 @@ func (t *Table) SweepNextBatch(expiration time.Duration) (swept, events, objects
 	if !t.opened() {
 		return
 	}
+	var policy = &RetentionPolicy{Name: "default", Duration: expiration}
 	t.Update(func(tx *Tx) error {
-		var bound = ShiftTimeBytes(time.Now().Add(-expiration))
-		// Find next object in current shard.
-		var sb = tx.Bucket(shardDBName(t.currentShard))
-		var sc = sb.Cursor()
-		for ; swept < SweepBatchSize && events < SweepBatchSize; swept += 1 {
-			var objectKey []byte
-			if t.currentObject == nil {
-				objectKey, _ = sc.First()
-			} else {
-				sc.Seek(t.currentObject)
-				objectKey, _ = sc.Next()
-			}
-			// If current shard is exhausted, move to the next one.
-			if objectKey == nil {
-				// If this was the last shard, roll over to the first shard.
-				t.currentShard = (t.currentShard + 1) % t.ShardCount()
-				t.currentObject = nil
-				sb = tx.Bucket(shardDBName(t.currentShard))
-				sc = sb.Cursor()
-				statsd.Count("expiration.rollover", 1, t.ddTags())
-				continue // Hitting the end of the shard counts as an object sweep too.
-			}
-			// Clone the key as it needs to outlive its transaction.
-			t.currentObject = append([]byte(nil), objectKey...)
-			var ob = sb.Bucket(objectKey)
-			var oc = ob.Cursor()
-			var eventKey []byte
-			// Now iterate over the events from the begining until event timestamp reaches the bound
-			// and delete everything along the way.
-			for eventKey, _ = oc.First(); eventKey != nil && bytes.Compare(eventKey, bound) < 0; eventKey, _ = oc.Next() {
-				oc.Delete()
-				events++
-			}
-			if eventKey == nil { // current object is empty, nuke it.
-				sb.DeleteBucket(objectKey)
-				objects++
-			}
-		}
-		statsd.Count("expiration.sweep", 1, t.ddTags())
+		var p = t.retentionProgressFor(policy.Name)
+		swept, events, objects, _ = t.sweepBatch(tx, policy, p)
 
 		// It is better to trigger a rollback when nothing is deleted
 		if events == 0 && objects == 0 {
 			return NoDeletes
 		}
-		if events > 0 {
-			statsd.Count("expiration.events", int64(events), t.ddTags())
-		}
-		if objects > 0 {
-			statsd.Count("expiration.objects", int64(objects), t.ddTags())
-		}
 		return nil
 	})
 	return
 }
 
+// retentionProgressFor returns the sweep progress tracker for a policy,
+// creating one if this is the first sweep against it.
+func (t *Table) retentionProgressFor(name string) *retentionProgress {
+	if t.retentionProgress == nil {
+		t.retentionProgress = make(map[string]*retentionProgress)
+	}
+	p, ok := t.retentionProgress[name]
+	if !ok {
+		p = &retentionProgress{}
+		t.retentionProgress[name] = p
+	}
+	return p
+}
+
+// sweepBatch sweeps events older than policy's bound, resuming from the
+// shard and object recorded in p and rolling over into the following
+// shards as each one is exhausted, and advances p in place. It
+// reports per-policy stats to statsd. Rate limiting is deliberately not
+// done here: this runs inside a live Bolt write transaction under the
+// table lock, and sleeping in either place would stall every other
+// table operation for as long as the throttle stretches the sweep out.
+// Callers that want to rate-limit a sweep (see RetentionService) should
+// throttle between calls to sweepBatch instead, using the bytes this
+// call reports it deleted.
+func (t *Table) sweepBatch(tx *Tx, policy *RetentionPolicy, p *retentionProgress) (swept, events, objects int, deletedBytes int64) {
+	var bound = ShiftTimeBytes(time.Now().Add(-policy.Duration))
+	var tags = t.ddPolicyTags(policy.Name)
+
+	// A policy scoped to a Measurement only sweeps events carrying that
+	// property. If the property no longer exists, nothing can carry it,
+	// so the policy matches no events rather than falling back to
+	// sweeping everything.
+	var measurementPropertyID int
+	var measurementKnown = true
+	if policy.Measurement != "" {
+		prop, ok := t.properties[policy.Measurement]
+		if !ok {
+			measurementKnown = false
+		} else {
+			measurementPropertyID = prop.ID
+		}
+	}
+
+	var sb = tx.Bucket(shardDBName(p.currentShard))
+	var sc = sb.Cursor()
+	for ; swept < SweepBatchSize && events < SweepBatchSize; swept += 1 {
+		var objectKey []byte
+		if p.currentObject == nil {
+			objectKey, _ = sc.First()
+		} else {
+			sc.Seek(p.currentObject)
+			objectKey, _ = sc.Next()
+		}
+		// If current shard is exhausted, move to the next one.
+		if objectKey == nil {
+			// If this was the last shard, roll over to the first shard.
+			p.currentShard = (p.currentShard + 1) % t.ShardCount()
+			p.currentObject = nil
+			sb = tx.Bucket(shardDBName(p.currentShard))
+			sc = sb.Cursor()
+			t.emitCount("expiration.rollover", 1, tags)
+			continue // Hitting the end of the shard counts as an object sweep too.
+		}
+		// Clone the key as it needs to outlive its transaction.
+		p.currentObject = append([]byte(nil), objectKey...)
+		var ob = sb.Bucket(objectKey)
+		var oc = ob.Cursor()
+		var eventKey, eventValue []byte
+		var skipped bool
+		// Now iterate over the events from the begining until event timestamp reaches the bound
+		// and delete everything along the way, except events a Measurement-scoped policy doesn't cover.
+		for eventKey, eventValue = oc.First(); eventKey != nil && bytes.Compare(eventKey, bound) < 0; eventKey, eventValue = oc.Next() {
+			if policy.Measurement != "" {
+				if !measurementKnown {
+					skipped = true
+					continue
+				}
+				var raw rawEvent
+				if err := raw.unmarshal(eventValue); err != nil {
+					skipped = true
+					continue
+				}
+				if _, ok := raw.data[measurementPropertyID]; !ok {
+					skipped = true
+					continue
+				}
+			}
+			deletedBytes += int64(len(eventKey) + len(eventValue))
+			oc.Delete()
+			events++
+		}
+		if eventKey == nil && !skipped { // current object is empty, nuke it.
+			sb.DeleteBucket(objectKey)
+			objects++
+		}
+	}
+	t.emitCount("expiration.sweep", 1, tags)
+	if events > 0 {
+		t.emitCount("expiration.events", int64(events), tags)
+	}
+	if objects > 0 {
+		t.emitCount("expiration.objects", int64(objects), tags)
+	}
+	return
+}
+
 // Gather storage stats from bolt. Account only for data buckets if parameter all is false,
 // otherwise include everything (factors and meta buckets).
 func (t *Table) Stats(all bool) (*TableStats, error) {
@@ -207,6 +322,32 @@ func (t *Table) Stats(all bool) (*TableStats, error) {
 	return stats, nil
 }
 
+// EngineStats returns the subset of Stats that applies regardless of
+// which storage engine backs the table. It is the engine-agnostic
+// counterpart to Stats, which exposes Bolt-specific page internals. When
+// the table has an engine installed, this delegates to it directly
+// rather than re-deriving the same numbers from Stats.
+func (t *Table) EngineStats() (engine.Stats, error) {
+	if t.engine != nil {
+		return t.engine.Stats()
+	}
+
+	stats, err := t.Stats(true)
+	if err != nil {
+		return engine.Stats{}, err
+	}
+	return engine.Stats{
+		KeyCount:       stats.KeyCount,
+		Depth:          stats.Depth,
+		BranchPages:    stats.BranchPages,
+		BranchOverflow: stats.BranchOverflow,
+		LeafPages:      stats.LeafPages,
+		LeafOverflow:   stats.LeafOverflow,
+		FreePages:      stats.FreePages,
+		PendingPages:   stats.PendingPages,
+	}, nil
+}
+
 // Name returns the name of the table.
 func (t *Table) Name() string {
 	return t.name
@@ -269,15 +410,25 @@ func (t *Table) open() error {
 	if t.db != nil {
 		return nil
 	}
+	if t.engineName != "" && t.engineName != EngineBolt {
+		return fmt.Errorf("table open: engine %q is not yet wired into Table, only %q is supported", t.engineName, EngineBolt)
+	}
 
-	// Create Bolt database.
-	db, err := bolt.Open(t.path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	// Open the storage engine. boltengine.Engine wraps exactly the same
+	// *bolt.DB Table drove directly before the engine abstraction was
+	// introduced, so t.db keeps working unchanged everywhere else in the
+	// package; Table just no longer constructs it inline.
+	eng := boltengine.New()
+	err := eng.Open(t.path, t.engineOpts)
 	if err != nil {
 		return fmt.Errorf("table open: %s", err)
 	}
-	db.FillPercent = 0.9
-	db.StrictMode = t.StrictMode
-	t.db = db
+	if _, ok := t.engineOpts["fillPercent"].(float64); !ok {
+		eng.DB().FillPercent = 0.9
+	}
+	eng.DB().StrictMode = t.StrictMode
+	t.engine = eng
+	t.db = eng.DB()
 
 	// Initialize stats
 	stats := t.db.Stats()
@@ -359,6 +510,10 @@ func (t *Table) Close() {
 }
 
 func (t *Table) close() {
+	if t.engine != nil {
+		t.engine.Close()
+		return
+	}
 	if t.db != nil {
 		t.db.Close()
 	}
@@ -376,10 +531,37 @@ func (t *Table) Update(fn func(*Tx) error) error {
 	err := t.db.Update(func(tx *bolt.Tx) error {
 		return fn(&Tx{tx, t})
 	})
+	if err == nil {
+		t.maybeSync()
+	}
 	t.ddEmitStats()
 	return err
 }
 
+// maybeSync forces an explicit Bolt Sync every syncEvery commits while
+// NoSync mode is enabled. NoSync only skips the fsync Bolt would
+// otherwise do on every commit; without this, dirty pages could go
+// unflushed indefinitely. It is driven from Update itself so every write
+// path is covered - InsertEvents, retention sweeps, resharding, and
+// property/factor creation alike - not just the ingest queue's batched
+// commits.
+func (t *Table) maybeSync() {
+	t.syncMu.Lock()
+	if !t.noSync || t.syncEvery <= 0 {
+		t.syncMu.Unlock()
+		return
+	}
+	t.sinceSync++
+	due := t.sinceSync >= t.syncEvery
+	if due {
+		t.sinceSync = 0
+	}
+	t.syncMu.Unlock()
+	if due {
+		go t.db.Sync()
+	}
+}
+
 // MaxTransientID returns the largest transient property identifier.
 func (t *Table) MaxTransientID() int {
 	return t.maxTransientID
@@ -392,10 +574,22 @@ func (t *Table) MaxPermanentID() int {
 
 // marshal encodes the table into a byte slice.
 func (t *Table) marshal() ([]byte, error) {
-	var msg = tableRawMessage{Name: t.name, ShardCount: t.shardCount, MaxPermanentID: t.maxPermanentID, MaxTransientID: t.maxTransientID}
+	var msg = tableRawMessage{
+		Name:             t.name,
+		ShardCount:       t.shardCount,
+		ShardingStrategy: t.shardingStrategy,
+		MaxPermanentID:   t.maxPermanentID,
+		MaxTransientID:   t.maxTransientID,
+		ReshardNewCount:  t.reshardNewCount,
+		ReshardFromShard: t.reshardFromShard,
+		ReshardCursor:    t.reshardCursor,
+	}
 	for _, p := range t.properties {
 		msg.Properties = append(msg.Properties, p)
 	}
+	for _, rp := range t.retentionPolicies {
+		msg.RetentionPolicies = append(msg.RetentionPolicies, rp)
+	}
 	return json.Marshal(msg)
 }
 
@@ -409,6 +603,10 @@ func (t *Table) unmarshal(data []byte) error {
 	t.maxPermanentID = msg.MaxPermanentID
 	t.maxTransientID = msg.MaxTransientID
 	t.shardCount = msg.ShardCount
+	t.shardingStrategy = msg.ShardingStrategy
+	t.reshardNewCount = msg.ReshardNewCount
+	t.reshardFromShard = msg.ReshardFromShard
+	t.reshardCursor = msg.ReshardCursor
 
 	t.properties = make(map[string]*Property)
 	t.propertiesByID = make(map[int]*Property)
@@ -418,6 +616,11 @@ func (t *Table) unmarshal(data []byte) error {
 		t.propertiesByID[p.ID] = p
 	}
 
+	t.retentionPolicies = make(map[string]*RetentionPolicy)
+	for _, rp := range msg.RetentionPolicies {
+		t.retentionPolicies[rp.Name] = rp
+	}
+
 	return nil
 }
 
@@ -436,8 +639,12 @@ func (t *Table) copyProperties() {
 	t.propertiesByID = propertiesByID
 }
 
-// shardIndex returns the appropriate shard for a given object id.
+// shardIndex returns the appropriate shard for a given object id, per the
+// table's configured ShardingStrategy.
 func (t *Table) shardIndex(id string) int {
+	if t.shardingStrategy == ShardingRendezvous {
+		return rendezvousShardIndex(id, t.shardCount)
+	}
 	return int(hash.Local(id)) % t.shardCount
 }
 
@@ -449,10 +656,23 @@ func (t *Table) ddTags() []string {
 	return t.ddTagsCache
 }
 
+// ddPolicyTags returns the DataDog tags for a metric scoped to a single
+// retention policy, in addition to the table's own tags.
+func (t *Table) ddPolicyTags(policy string) []string {
+	return append(append([]string{}, t.ddTags()...), "policy:"+policy)
+}
+
+// ddEmitStats diffs the table's Bolt stats against the previous snapshot
+// and reports the delta. Update calls this with no table lock held - the
+// ingest queue's flush timer and the retention service's ticker both call
+// Update from their own goroutines - so the read-modify-write of
+// t.boltStats needs its own lock rather than relying on the table lock.
 func (t *Table) ddEmitStats() {
+	t.statsMu.Lock()
 	var fresh = t.db.Stats()
 	var stats = fresh.Sub(t.boltStats)
 	t.boltStats = &fresh
+	t.statsMu.Unlock()
 
 	var tags = t.ddTags()
 	statsd.Gauge("bolt.pages.free", float64(stats.FreePageN), tags)
@@ -476,6 +696,65 @@ func (t *Table) ddEmitStats() {
 	statsd.Count("bolt.txn.write.count", int64(stats.TxStats.Write), tags)
 	statsd.Count("bolt.txn.write.time", int64(stats.TxStats.WriteTime), tags)
 	statsd.Histogram("bolt.txn.write.period", float64(stats.TxStats.WriteTime)/float64(stats.TxStats.Write), tags)
+
+	for _, sink := range t.sinks {
+		sink.Gauge("bolt.pages.free", float64(stats.FreePageN), tags)
+		sink.Gauge("bolt.pages.pending", float64(stats.PendingPageN), tags)
+		sink.Gauge("bolt.pages.free.alloc", float64(stats.FreeAlloc), tags)
+		sink.Gauge("bolt.pages.freelist.inuse", float64(stats.FreeAlloc), tags)
+		sink.Count("bolt.txn.total", int64(stats.TxN), tags)
+		sink.Gauge("bolt.txn.open", float64(stats.OpenTxN), tags)
+		sink.Count("bolt.txn.page.count", int64(stats.TxStats.PageCount), tags)
+		sink.Count("bolt.txn.page.alloc", int64(stats.TxStats.PageAlloc), tags)
+		sink.Count("bolt.txn.cursor.count", int64(stats.TxStats.CursorCount), tags)
+		sink.Count("bolt.txn.node.count", int64(stats.TxStats.NodeCount), tags)
+		sink.Count("bolt.txn.node.deref", int64(stats.TxStats.NodeDeref), tags)
+		sink.Count("bolt.txn.node.rebalance.count", int64(stats.TxStats.Rebalance), tags)
+		sink.Count("bolt.txn.node.rebalance.time", int64(stats.TxStats.RebalanceTime), tags)
+		sink.Count("bolt.txn.node.split", int64(stats.TxStats.Split), tags)
+		sink.Count("bolt.txn.node.spill.count", int64(stats.TxStats.Spill), tags)
+		sink.Count("bolt.txn.node.spill.time", int64(stats.TxStats.SpillTime), tags)
+		sink.Count("bolt.txn.write.count", int64(stats.TxStats.Write), tags)
+		sink.Count("bolt.txn.write.time", int64(stats.TxStats.WriteTime), tags)
+	}
+}
+
+// emitCount reports a counter to statsd and every registered metrics
+// sink, so callers outside of ddEmitStats (the expiration sweeper, the
+// ingest queue, ...) don't have to duplicate the fan-out.
+func (t *Table) emitCount(name string, value int64, tags []string) {
+	statsd.Count(name, value, tags)
+	for _, sink := range t.sinks {
+		sink.Count(name, value, tags)
+	}
+}
+
+// emitGauge reports a gauge to statsd and every registered metrics sink.
+func (t *Table) emitGauge(name string, value float64, tags []string) {
+	statsd.Gauge(name, value, tags)
+	for _, sink := range t.sinks {
+		sink.Gauge(name, value, tags)
+	}
+}
+
+// emitHistogram reports a histogram sample to statsd and every
+// registered metrics sink.
+func (t *Table) emitHistogram(name string, value float64, tags []string) {
+	statsd.Histogram(name, value, tags)
+	for _, sink := range t.sinks {
+		sink.Histogram(name, value, tags)
+	}
+}
+
+// AddMetricsSink registers an additional destination for the table's
+// operational metrics (bolt internals, expiration counters, ingest batch
+// sizes, ...), alongside the statsd emission Table always performs. This
+// lets, e.g., a Prometheus scraper observe the same counters without
+// running a statsd relay.
+func (t *Table) AddMetricsSink(sink metrics.Sink) {
+	t.Lock()
+	defer t.Unlock()
+	t.sinks = append(t.sinks, sink)
 }
 
 // shardDBName returns the name of the shard table.
@@ -499,11 +778,16 @@ func reverseFactorKey(index int) []byte {
 }
 
 type tableRawMessage struct {
-	Name           string      `json:"name"`
-	ShardCount     int         `json:"shardCount"`
-	MaxPermanentID int         `json:"maxPermanentID"`
-	MaxTransientID int         `json:"maxTransientID"`
-	Properties     []*Property `json:"properties"`
+	Name              string             `json:"name"`
+	ShardCount        int                `json:"shardCount"`
+	ShardingStrategy  ShardingStrategy   `json:"shardingStrategy,omitempty"`
+	MaxPermanentID    int                `json:"maxPermanentID"`
+	MaxTransientID    int                `json:"maxTransientID"`
+	Properties        []*Property        `json:"properties"`
+	RetentionPolicies []*RetentionPolicy `json:"retentionPolicies,omitempty"`
+	ReshardNewCount   int                `json:"reshardNewCount,omitempty"`
+	ReshardFromShard  int                `json:"reshardFromShard,omitempty"`
+	ReshardCursor     []byte             `json:"reshardCursor,omitempty"`
 }
 
 // Event represents the state for an object at a given point in time.
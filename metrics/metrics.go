@@ -0,0 +1,23 @@
+// Package metrics defines a small sink abstraction so that callers
+// emitting operational metrics (page counts, transaction timings,
+// expiration/ingest counters, ...) aren't hard-wired to a single metrics
+// backend. Tags follow the "key:value" convention already used
+// throughout Sky's statsd calls, e.g. "table:events".
+package metrics
+
+// Sink receives gauge, counter, and histogram samples. Implementations
+// must be safe for concurrent use, since a Table may emit from multiple
+// goroutines (the request path, the retention service, the ingest
+// queue, ...).
+type Sink interface {
+	// Gauge records the current value of a point-in-time measurement,
+	// such as a page count.
+	Gauge(name string, value float64, tags []string)
+
+	// Count records an incremental counter, such as events swept.
+	Count(name string, value int64, tags []string)
+
+	// Histogram records a sample for a distribution, such as a
+	// transaction's commit latency.
+	Histogram(name string, value float64, tags []string)
+}
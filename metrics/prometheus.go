@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusSink adapts a prometheus.Registerer to the Sink interface.
+// Metric names are registered lazily on first use, as GaugeVec/CounterVec
+// /HistogramVec keyed on whatever label names appear in that metric's
+// first sample's tags (typically "table" and, for retention-scoped
+// metrics, "policy").
+type PrometheusSink struct {
+	registerer prometheus.Registerer
+
+	mu         sync.Mutex
+	gauges     map[string]*prometheus.GaugeVec
+	counters   map[string]*prometheus.CounterVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+// NewPrometheusSink returns a Sink backed by reg.
+func NewPrometheusSink(reg prometheus.Registerer) *PrometheusSink {
+	return &PrometheusSink{
+		registerer: reg,
+		gauges:     make(map[string]*prometheus.GaugeVec),
+		counters:   make(map[string]*prometheus.CounterVec),
+		histograms: make(map[string]*prometheus.HistogramVec),
+	}
+}
+
+// tagLabels splits "key:value" tags into parallel label name/value
+// slices suitable for a Vec's With().
+func tagLabels(tags []string) (names, values []string) {
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		names = append(names, parts[0])
+		values = append(values, parts[1])
+	}
+	return names, values
+}
+
+// metricName converts a statsd-style dotted name ("bolt.pages.free")
+// into a Prometheus-style name ("bolt_pages_free").
+func metricName(name string) string {
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+// Gauge implements Sink.
+func (s *PrometheusSink) Gauge(name string, value float64, tags []string) {
+	names, values := tagLabels(tags)
+	s.mu.Lock()
+	vec, ok := s.gauges[name]
+	if !ok {
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: metricName(name)}, names)
+		s.registerer.MustRegister(vec)
+		s.gauges[name] = vec
+	}
+	s.mu.Unlock()
+	vec.WithLabelValues(values...).Set(value)
+}
+
+// Count implements Sink.
+func (s *PrometheusSink) Count(name string, value int64, tags []string) {
+	names, values := tagLabels(tags)
+	s.mu.Lock()
+	vec, ok := s.counters[name]
+	if !ok {
+		vec = prometheus.NewCounterVec(prometheus.CounterOpts{Name: metricName(name)}, names)
+		s.registerer.MustRegister(vec)
+		s.counters[name] = vec
+	}
+	s.mu.Unlock()
+	vec.WithLabelValues(values...).Add(float64(value))
+}
+
+// Histogram implements Sink.
+func (s *PrometheusSink) Histogram(name string, value float64, tags []string) {
+	names, values := tagLabels(tags)
+	s.mu.Lock()
+	vec, ok := s.histograms[name]
+	if !ok {
+		vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: metricName(name)}, names)
+		s.registerer.MustRegister(vec)
+		s.histograms[name] = vec
+	}
+	s.mu.Unlock()
+	vec.WithLabelValues(values...).Observe(value)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// TestPrometheusSink_GaugeCountHistogram confirms tag-to-label conversion
+// and that repeat calls for the same metric name reuse the registered Vec
+// instead of re-registering (which would panic on the second call).
+func TestPrometheusSink_GaugeCountHistogram(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	sink := NewPrometheusSink(reg)
+
+	sink.Gauge("backup.bytes", 100, []string{"table:events"})
+	sink.Gauge("backup.bytes", 150, []string{"table:events"})
+	sink.Count("expiration.events", 3, []string{"table:events", "policy:default"})
+	sink.Histogram("ingest.batch.wait", 0.5, []string{"table:events"})
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %s", err)
+	}
+
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+
+	gauge, ok := byName["backup_bytes"]
+	if !ok {
+		t.Fatal("missing backup_bytes gauge")
+	}
+	if got := gauge.GetMetric()[0].GetGauge().GetValue(); got != 150 {
+		t.Fatalf("expected last-write-wins value 150, got %v", got)
+	}
+
+	counter, ok := byName["expiration_events"]
+	if !ok {
+		t.Fatal("missing expiration_events counter")
+	}
+	var sawPolicyLabel bool
+	for _, l := range counter.GetMetric()[0].GetLabel() {
+		if l.GetName() == "policy" && l.GetValue() == "default" {
+			sawPolicyLabel = true
+		}
+	}
+	if !sawPolicyLabel {
+		t.Fatalf("expected policy:default tag to become a label, got %+v", counter.GetMetric()[0].GetLabel())
+	}
+
+	if _, ok := byName["ingest_batch_wait"]; !ok {
+		t.Fatal("missing ingest_batch_wait histogram")
+	}
+}
+
+func TestTagLabels(t *testing.T) {
+	names, values := tagLabels([]string{"table:events", "policy:default", "malformed"})
+	if len(names) != 2 || len(values) != 2 {
+		t.Fatalf("expected malformed tag to be skipped, got names=%v values=%v", names, values)
+	}
+	if names[0] != "table" || values[0] != "events" {
+		t.Fatalf("unexpected first pair: %s=%s", names[0], values[0])
+	}
+}
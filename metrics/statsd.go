@@ -0,0 +1,28 @@
+package metrics
+
+import "github.com/skydb/sky/statsd"
+
+// StatsdSink adapts Sky's existing statsd package to the Sink interface,
+// preserving the exact behavior Table had before sinks were pluggable.
+type StatsdSink struct{}
+
+// NewStatsdSink returns a Sink that forwards samples to the statsd
+// package's package-level client.
+func NewStatsdSink() *StatsdSink {
+	return &StatsdSink{}
+}
+
+// Gauge implements Sink.
+func (s *StatsdSink) Gauge(name string, value float64, tags []string) {
+	statsd.Gauge(name, value, tags)
+}
+
+// Count implements Sink.
+func (s *StatsdSink) Count(name string, value int64, tags []string) {
+	statsd.Count(name, value, tags)
+}
+
+// Histogram implements Sink.
+func (s *StatsdSink) Histogram(name string, value float64, tags []string) {
+	statsd.Histogram(name, value, tags)
+}